@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	p2p "github.com/assemblaj/GravitationProtocol/pb"
+
+	proto "github.com/golang/protobuf/proto"
+	uuid "github.com/google/uuid"
+	inet "github.com/libp2p/go-libp2p-net"
+	peer "github.com/libp2p/go-libp2p-peer"
+	protobufCodec "github.com/multiformats/go-multicodec/protobuf"
+	"go.uber.org/zap"
+)
+
+// gravitationAttest is the sub-protocol a peer uses to ask another peer to
+// sign a MembershipAttestation vouching for its own orbit membership.
+const gravitationAttest = "/gravitation/attest/0.0.1"
+
+// RequestAttestation asks attested to sign a MembershipAttestation stating
+// that it belongs to this node's orbit, so it can be safely reported in a
+// future GravitationRequest/Response SubOrbit. It returns an error if
+// attested refuses or the returned attestation fails verification.
+func (p *GravitationProtocol) RequestAttestation(ctx context.Context, attested peer.ID) (*p2p.MembershipAttestation, error) {
+	req := &p2p.AttestationRequest{
+		MessageData: p.node.NewMessageData(uuid.New().String(), false),
+		AttesterId:  p.node.ID().String(),
+	}
+
+	signature, err := p.node.signProtoMessage(req)
+	if err != nil {
+		return nil, err
+	}
+	req.MessageData.Sign = signature
+
+	s, err := p.node.NewStream(ctx, attested, gravitationAttest)
+	if err != nil {
+		return nil, err
+	}
+
+	if ok := p.node.sendProtoMessage(req, s); !ok {
+		s.Reset()
+		return nil, fmt.Errorf("failed to send attestation request to %s", attested)
+	}
+
+	// half-close our write side so onAttestationRequest's io.ReadAll(s) sees
+	// EOF after the request; attested writes its reply on this same stream
+	s.Close()
+
+	raw, err := io.ReadAll(s)
+	if err != nil {
+		s.Reset()
+		return nil, err
+	}
+
+	att := &p2p.MembershipAttestation{}
+	decoder := protobufCodec.Multicodec(nil).Decoder(bytes.NewReader(raw))
+	if err := decoder.Decode(att); err != nil {
+		return nil, err
+	}
+
+	if !verifyAttestation(att) {
+		return nil, fmt.Errorf("attestation from %s failed verification", attested)
+	}
+
+	return att, nil
+}
+
+// onAttestationRequest handles an incoming request to vouch for this
+// node's membership in the requester's orbit: it signs a
+// MembershipAttestation over its own peer ID and profile and replies with
+// it on the same stream the request arrived on.
+func (p *GravitationProtocol) onAttestationRequest(s inet.Stream) {
+	raw, err := io.ReadAll(s)
+	if err != nil {
+		s.Reset()
+		return
+	}
+
+	req := &p2p.AttestationRequest{}
+	decoder := protobufCodec.Multicodec(nil).Decoder(bytes.NewReader(raw))
+	if err := decoder.Decode(req); err != nil {
+		p.logger.Error("failed to decode attestation request", zap.Error(err))
+		s.Reset()
+		return
+	}
+
+	if !p.node.authenticateMessage(req, req.MessageData) {
+		authFailuresTotal.Inc()
+		p.logger.Warn("failed to authenticate attestation request", zap.Stringer("from", s.Conn().RemotePeer()))
+		s.Reset()
+		return
+	}
+
+	att := &p2p.MembershipAttestation{
+		MessageData: p.node.NewMessageData(uuid.New().String(), false),
+		PeerId:      p.node.ID().String(),
+		Profile:     p.profile,
+		AttesterId:  req.AttesterId,
+	}
+
+	signature, err := p.node.signProtoMessage(att)
+	if err != nil {
+		p.logger.Error("failed to sign attestation", zap.Error(err))
+		s.Reset()
+		return
+	}
+	att.MessageData.Sign = signature
+
+	if ok := p.node.sendProtoMessage(att, s); !ok {
+		s.Reset()
+		return
+	}
+	s.Close()
+}
+
+// subOrbitEntry is the common shape of a GravitationRequest_SubOrbit and a
+// GravitationResponse_SubOrbit entry, used so mergeAttestedSubOrbit can
+// work across both generated types.
+type subOrbitEntry struct {
+	peerID      string
+	profile     []string
+	attestation *p2p.MembershipAttestation
+}
+
+// mergeAttestedSubOrbit verifies each third-party entry's attestation
+// against its claimed peer ID and appends the ones that check out to
+// orbit, skipping peers already present. selfID is excluded since a
+// peer's own entry needs no attestation.
+func mergeAttestedSubOrbit(orbit []Body, selfID string, entries []subOrbitEntry) []Body {
+	known := make(map[string]bool, len(orbit))
+	for _, body := range orbit {
+		known[body.peerID] = true
+	}
+
+	for _, entry := range entries {
+		if entry.peerID == selfID || known[entry.peerID] {
+			continue
+		}
+
+		att := entry.attestation
+		if att == nil || att.PeerId != entry.peerID || !verifyAttestation(att) {
+			continue
+		}
+
+		orbit = append(orbit, Body{peerID: entry.peerID, profile: entry.profile, attestation: att})
+		known[entry.peerID] = true
+	}
+
+	return orbit
+}
+
+// verifyAttestation checks that att is signed by the peer it claims to be
+// attested for. Unlike authenticateMessage, it does not trust the
+// NodePubKey carried on the message: the public key is derived straight
+// from the claimed peer ID, so a relaying attester cannot forge an
+// attestation for a peer it does not control.
+func verifyAttestation(att *p2p.MembershipAttestation) bool {
+	claimed, err := peer.IDB58Decode(att.PeerId)
+	if err != nil {
+		return false
+	}
+
+	pubKey, err := claimed.ExtractPublicKey()
+	if err != nil {
+		return false
+	}
+
+	sig := att.MessageData.Sign
+	unsigned := &p2p.MembershipAttestation{
+		MessageData: &p2p.MessageData{
+			ClientVersion: att.MessageData.ClientVersion,
+			Timestamp:     att.MessageData.Timestamp,
+			Id:            att.MessageData.Id,
+			NodeId:        att.MessageData.NodeId,
+			NodePubKey:    att.MessageData.NodePubKey,
+		},
+		PeerId:     att.PeerId,
+		Profile:    att.Profile,
+		AttesterId: att.AttesterId,
+	}
+
+	data, err := proto.Marshal(unsigned)
+	if err != nil {
+		return false
+	}
+
+	ok, err := pubKey.Verify(data, sig)
+	return err == nil && ok
+}
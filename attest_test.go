@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+)
+
+// TestRequestAttestation exercises a full RequestAttestation/onAttestationRequest
+// round trip and checks that the returned attestation verifies.
+func TestRequestAttestation(t *testing.T) {
+	mn := mocknet.New()
+	defer mn.Close()
+
+	requester := newTestProtocol(t, mn, []string{"earth"})
+	attester := newTestProtocol(t, mn, []string{"earth"})
+
+	if err := mn.LinkAll(); err != nil {
+		t.Fatalf("LinkAll: %s", err)
+	}
+	if err := mn.ConnectAllButSelf(); err != nil {
+		t.Fatalf("ConnectAllButSelf: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	att, err := requester.RequestAttestation(ctx, attester.node.ID())
+	if err != nil {
+		t.Fatalf("RequestAttestation: %s", err)
+	}
+
+	if att.PeerId != attester.node.ID().String() {
+		t.Errorf("attestation PeerId = %q, want %q", att.PeerId, attester.node.ID().String())
+	}
+	if !verifyAttestation(att) {
+		t.Error("attestation failed verification")
+	}
+}
+
+// TestRequestAttestationRejectsWrongAttester checks that an attestation
+// relabeled by the attester with a peerId that doesn't match its actual
+// signer is rejected by verifyAttestation.
+func TestRequestAttestationRejectsWrongAttester(t *testing.T) {
+	mn := mocknet.New()
+	defer mn.Close()
+
+	attester := newTestProtocol(t, mn, []string{"earth"})
+	impostor := newTestProtocol(t, mn, []string{"earth"})
+
+	if err := mn.LinkAll(); err != nil {
+		t.Fatalf("LinkAll: %s", err)
+	}
+	if err := mn.ConnectAllButSelf(); err != nil {
+		t.Fatalf("ConnectAllButSelf: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	att, err := attester.RequestAttestation(ctx, impostor.node.ID())
+	if err != nil {
+		t.Fatalf("RequestAttestation: %s", err)
+	}
+
+	att.PeerId = attester.node.ID().String()
+	if verifyAttestation(att) {
+		t.Error("verifyAttestation accepted an attestation relabeled with a peerId that didn't sign it")
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	discovery "github.com/libp2p/go-libp2p-discovery"
+	dht "github.com/libp2p/go-libp2p-kad-dht"
+	peerstore "github.com/libp2p/go-libp2p-peerstore"
+	"go.uber.org/zap"
+)
+
+// rendezvousPollInterval is how often a node polls the DHT for peers
+// under the gravitation network's rendezvous string once bootstrapped.
+const rendezvousPollInterval = time.Minute
+
+// Run bootstraps the DHT, dials the configured bootstrap peers, advertises
+// NetworkID as a rendezvous point, and continuously dials peers found
+// under that rendezvous via Gravitation. It blocks until ctx is done.
+func (p *GravitationProtocol) Run(ctx context.Context) error {
+	kadDHT, err := dht.New(ctx, p.node)
+	if err != nil {
+		return err
+	}
+
+	if err := kadDHT.Bootstrap(ctx); err != nil {
+		return err
+	}
+
+	if err := p.bootstrapPeers(ctx); err != nil {
+		return err
+	}
+
+	routingDiscovery := discovery.NewRoutingDiscovery(kadDHT)
+	discovery.Advertise(ctx, routingDiscovery, p.networkID)
+
+	ticker := time.NewTicker(rendezvousPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			p.findAndGravitate(ctx, routingDiscovery)
+		}
+	}
+}
+
+// bootstrapPeers dials every configured bootstrap multiaddr so the DHT has
+// an initial set of peers to route through.
+func (p *GravitationProtocol) bootstrapPeers(ctx context.Context) error {
+	for _, addr := range p.bootstrapAddrs {
+		peerInfo, err := peerstore.InfoFromP2pAddr(addr)
+		if err != nil {
+			p.logger.Error("invalid bootstrap multiaddr", zap.Stringer("addr", addr), zap.Error(err))
+			continue
+		}
+
+		if err := p.node.Connect(ctx, *peerInfo); err != nil {
+			p.logger.Warn("failed to connect to bootstrap peer", zap.Stringer("peer", peerInfo.ID), zap.Error(err))
+			continue
+		}
+	}
+	return nil
+}
+
+// findAndGravitate looks up peers advertised under the gravitation
+// network's rendezvous, connects to each one other than this node, and
+// gravitates towards every peer it could connect to.
+func (p *GravitationProtocol) findAndGravitate(ctx context.Context, routingDiscovery *discovery.RoutingDiscovery) {
+	peerChan, err := routingDiscovery.FindPeers(ctx, p.networkID)
+	if err != nil {
+		p.logger.Error("failed to find peers under rendezvous", zap.String("network_id", p.networkID), zap.Error(err))
+		return
+	}
+
+	for peerInfo := range peerChan {
+		if peerInfo.ID == p.node.ID() {
+			continue
+		}
+
+		if err := p.node.Connect(ctx, peerInfo); err != nil {
+			continue
+		}
+
+		p.GravitationTo(peerInfo.ID)
+	}
+}
@@ -1,20 +1,30 @@
 package main
 
 import (
-	"bufio"
+	"bytes"
 	"context"
-	"log"
+	"io"
 	"reflect"
 	"sort"
+	"sync"
+	"time"
 
 	p2p "github.com/assemblaj/GravitationProtocol/pb"
 
 	uuid "github.com/google/uuid"
 	host "github.com/libp2p/go-libp2p-host"
 	inet "github.com/libp2p/go-libp2p-net"
+	peer "github.com/libp2p/go-libp2p-peer"
+	multiaddr "github.com/multiformats/go-multiaddr"
 	protobufCodec "github.com/multiformats/go-multicodec/protobuf"
+	"go.uber.org/zap"
 )
 
+// requestTimeout bounds how long a single Gravitation() request waits for
+// its response before it is dropped from the pending requests. It also
+// bounds opening each request and response stream.
+const requestTimeout = 15 * time.Second
+
 // pattern: /protocol-name/request-or-response-message/version
 const gravitationRequest = "/gravitation/gravitationreq/0.0.1"
 const gravitationResponse = "/gravitation/gravitationresp/0.0.1"
@@ -24,19 +34,27 @@ type gravitateReq func(profile []string, orbit []Body, data p2p.GravitationReque
 type gravitateRes func(profile []string, orbit []Body, data p2p.GravitationResponse) bool
 
 type Body struct {
-	peerID  string
-	profile []string
+	peerID      string
+	profile     []string
+	attestation *p2p.MembershipAttestation // signed proof peerID agreed to be reported in this orbit, nil for peers added directly from their own request, response or advertisement
 }
 
 // GravitationProtocol type
 type GravitationProtocol struct {
-	node        *Node                              // local host
-	requests    map[string]*p2p.GravitationRequest // used to access request data from response handlers
-	done        chan bool                          // only for demo purposes to stop main from terminating
-	profile     []string
-	orbit       []Body
-	reqCallback gravitateReq
-	resCallback gravitateRes
+	node           *Node                              // local host
+	requestsMu     sync.Mutex                         // guards requests, cancels and sentAt
+	requests       map[string]*p2p.GravitationRequest // used to access request data from response handlers
+	cancels        map[string]context.CancelFunc      // cancels the NewStream context for an in-flight request
+	done           chan bool                          // only for demo purposes to stop main from terminating
+	profile        []string
+	orbitMu        sync.Mutex // guards orbit; every stream handler runs on its own goroutine
+	orbit          []Body
+	reqCallback    gravitateReq
+	resCallback    gravitateRes
+	networkID      string                // DHT rendezvous string for this gravitation network
+	bootstrapAddrs []multiaddr.Multiaddr // multiaddrs dialed before joining the DHT
+	sentAt         map[string]time.Time  // request id -> send time, for gravitation_request_duration_seconds
+	logger         *zap.Logger
 }
 
 func gravitateIfEqualReq(profile []string, orbit []Body, data p2p.GravitationRequest) bool {
@@ -58,65 +76,172 @@ func gravitateIfEqualRes(profile []string, orbit []Body, data p2p.GravitationRes
 }
 
 // Create instance of protocol
-func NewGravitationProtocol(node *Node, done chan bool, profile []string, orbit []Body) *GravitationProtocol {
+// networkID is the DHT rendezvous string peers advertise and search under
+// to form a gravitation network, and bootstrapPeers seeds the DHT's
+// routing table before the first FindPeers call. logger may be nil, in
+// which case a no-op logger is used.
+func NewGravitationProtocol(node *Node, done chan bool, profile []string, orbit []Body, networkID string, bootstrapPeers []multiaddr.Multiaddr, logger *zap.Logger) *GravitationProtocol {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
 	p := &GravitationProtocol{
-		node:        node,
-		requests:    make(map[string]*p2p.GravitationRequest),
-		done:        done,
-		orbit:       orbit,
-		profile:     profile,
-		reqCallback: gravitateIfEqualReq,
-		resCallback: gravitateIfEqualRes}
+		node:           node,
+		requests:       make(map[string]*p2p.GravitationRequest),
+		cancels:        make(map[string]context.CancelFunc),
+		done:           done,
+		orbit:          orbit,
+		profile:        profile,
+		reqCallback:    gravitateIfEqualReq,
+		resCallback:    gravitateIfEqualRes,
+		networkID:      networkID,
+		bootstrapAddrs: bootstrapPeers,
+		sentAt:         make(map[string]time.Time),
+		logger:         logger}
 
 	node.SetStreamHandler(gravitationRequest, p.onGravitationRequest)
 	node.SetStreamHandler(gravitationResponse, p.onGravitationResponse)
+	node.SetStreamHandler(gravitationAttest, p.onAttestationRequest)
 	return p
 }
 
+// Close cancels any in-flight Gravitation requests and releases their
+// stream contexts. It does not close the underlying node.
+func (p *GravitationProtocol) Close() {
+	p.requestsMu.Lock()
+	defer p.requestsMu.Unlock()
+
+	for id, cancel := range p.cancels {
+		cancel()
+		delete(p.cancels, id)
+		delete(p.requests, id)
+		delete(p.sentAt, id)
+	}
+}
+
+// evictOnTimeout prunes requests/cancels/sentAt for id once ctx expires
+// without a response ever arriving. If the response handler already
+// removed id (cancelling ctx itself to unblock this goroutine), ctx.Err()
+// is context.Canceled rather than context.DeadlineExceeded and this is a
+// no-op.
+func (p *GravitationProtocol) evictOnTimeout(ctx context.Context, id string) {
+	<-ctx.Done()
+	if ctx.Err() != context.DeadlineExceeded {
+		return
+	}
+
+	p.requestsMu.Lock()
+	_, pending := p.requests[id]
+	if pending {
+		delete(p.requests, id)
+		delete(p.cancels, id)
+		delete(p.sentAt, id)
+	}
+	p.requestsMu.Unlock()
+
+	if pending {
+		p.logger.Warn("gravitation request timed out awaiting response", zap.String("message_id", id))
+	}
+}
+
+// orbitSnapshot returns a copy of the current orbit, safe to range over or
+// hand to reqCallback/resCallback without holding orbitMu.
+func (p *GravitationProtocol) orbitSnapshot() []Body {
+	p.orbitMu.Lock()
+	defer p.orbitMu.Unlock()
+
+	snapshot := make([]Body, len(p.orbit))
+	copy(snapshot, p.orbit)
+	return snapshot
+}
+
+// addToOrbit appends body to the orbit under orbitMu and keeps the
+// gravitation_orbit_size gauge in sync.
+func (p *GravitationProtocol) addToOrbit(body Body) {
+	p.orbitMu.Lock()
+	p.orbit = append(p.orbit, body)
+	orbitSize.Set(float64(len(p.orbit)))
+	p.orbitMu.Unlock()
+}
+
+// mergeOrbitSubOrbit verifies and folds third-party SubOrbit entries into
+// the orbit under orbitMu.
+func (p *GravitationProtocol) mergeOrbitSubOrbit(entries []subOrbitEntry) {
+	p.orbitMu.Lock()
+	p.orbit = mergeAttestedSubOrbit(p.orbit, p.node.ID().String(), entries)
+	orbitSize.Set(float64(len(p.orbit)))
+	p.orbitMu.Unlock()
+}
+
 // remote peer requests handler
 func (p *GravitationProtocol) onGravitationRequest(s inet.Stream) {
 
-	// get request data
-	data := &p2p.GravitationRequest{}
-	decoder := protobufCodec.Multicodec(nil).Decoder(bufio.NewReader(s))
-	err := decoder.Decode(data)
+	// read the full request before decoding so the stream can be closed
+	// as soon as possible, rather than held open behind a buffered reader
+	raw, err := io.ReadAll(s)
 	if err != nil {
-		log.Println(err)
+		p.logger.Error("failed to read gravitation request", zap.Error(err))
+		s.Reset()
+		return
+	}
+	s.Close()
+
+	data := &p2p.GravitationRequest{}
+	decoder := protobufCodec.Multicodec(nil).Decoder(bytes.NewReader(raw))
+	if err := decoder.Decode(data); err != nil {
+		p.logger.Error("failed to decode gravitation request", zap.Error(err))
 		return
 	}
 
-	log.Printf("%s: Received gravitation request from %s. Profile: %s SubOrbit: %s.", s.Conn().LocalPeer(), s.Conn().RemotePeer(), data.Profile, data.SubOrbit)
+	requestsReceivedTotal.Inc()
+	p.logger.Info("received gravitation request",
+		zap.Stringer("from", s.Conn().RemotePeer()),
+		zap.Strings("profile", data.Profile),
+		zap.Int("suborbit_size", len(data.SubOrbit)))
 
 	valid := p.node.authenticateMessage(data, data.MessageData)
 
 	if !valid {
-		log.Println("Failed to authenticate message")
+		authFailuresTotal.Inc()
+		p.logger.Warn("failed to authenticate gravitation request", zap.Stringer("from", s.Conn().RemotePeer()))
 		return
 	}
 
-	if p.reqCallback(p.profile, p.orbit, *data) {
-		p.orbit = append(p.orbit, Body{peerID: s.Conn().RemotePeer().String(), profile: data.Profile})
+	if p.reqCallback(p.profile, p.orbitSnapshot(), *data) {
+		p.addToOrbit(Body{peerID: s.Conn().RemotePeer().String(), profile: data.Profile})
+	}
+
+	// third-party SubOrbit entries are only trustworthy if the claimed
+	// peer attested to them itself; drop anything that doesn't verify
+	// before folding them into our own orbit
+	entries := make([]subOrbitEntry, len(data.SubOrbit))
+	for i, body := range data.SubOrbit {
+		entries[i] = subOrbitEntry{peerID: body.PeerId, profile: body.Profile, attestation: body.Attestation}
 	}
+	p.mergeOrbitSubOrbit(entries)
 
 	// generate response message
 
 	suborbit := []*p2p.GravitationResponse_SubOrbit{}
-	for _, body := range p.orbit {
+	for _, body := range p.orbitSnapshot() {
 		suborbit = append(suborbit, &(p2p.GravitationResponse_SubOrbit{
-			PeerId:  body.peerID,
-			Profile: body.profile}))
+			PeerId:      body.peerID,
+			Profile:     body.profile,
+			Attestation: body.attestation}))
 	}
 
 	resp := &p2p.GravitationResponse{MessageData: p.node.NewMessageData(data.MessageData.Id, false),
 		Profile:  p.profile,
 		SubOrbit: suborbit}
 
-	log.Printf("%s: Sending gravitation response to %s. Message id: %s Profile: %s SubOrbit: %s....", s.Conn().LocalPeer(), s.Conn().RemotePeer(), data.MessageData.Id, resp.Profile, resp.SubOrbit)
+	p.logger.Debug("sending gravitation response",
+		zap.Stringer("to", s.Conn().RemotePeer()),
+		zap.String("message_id", data.MessageData.Id))
 
 	// sign the data
 	signature, err := p.node.signProtoMessage(resp)
 	if err != nil {
-		log.Println("failed to sign response")
+		p.logger.Error("failed to sign gravitation response", zap.Error(err))
 		return
 	}
 
@@ -124,50 +249,85 @@ func (p *GravitationProtocol) onGravitationRequest(s inet.Stream) {
 	resp.MessageData.Sign = signature
 
 	// send the response
-	s, respErr := p.node.NewStream(context.Background(), s.Conn().RemotePeer(), gravitationResponse)
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	s, respErr := p.node.NewStream(ctx, s.Conn().RemotePeer(), gravitationResponse)
 	if respErr != nil {
-		log.Println(respErr)
+		p.logger.Error("failed to open gravitation response stream", zap.Error(respErr))
 		return
 	}
 
 	ok := p.node.sendProtoMessage(resp, s)
-
-	if ok {
-		log.Printf("%s: Gravitation response to %s sent.", s.Conn().LocalPeer().String(), s.Conn().RemotePeer().String())
+	if !ok {
+		s.Reset()
+		return
 	}
+
+	s.Close()
+	p.logger.Info("gravitation response sent", zap.Stringer("to", s.Conn().RemotePeer()))
 }
 
 // remote gravitation response handler
 func (p *GravitationProtocol) onGravitationResponse(s inet.Stream) {
-	data := &p2p.GravitationResponse{}
-	decoder := protobufCodec.Multicodec(nil).Decoder(bufio.NewReader(s))
-	err := decoder.Decode(data)
+	raw, err := io.ReadAll(s)
 	if err != nil {
+		s.Reset()
+		return
+	}
+	s.Close()
+
+	data := &p2p.GravitationResponse{}
+	decoder := protobufCodec.Multicodec(nil).Decoder(bytes.NewReader(raw))
+	if err := decoder.Decode(data); err != nil {
 		return
 	}
 
 	valid := p.node.authenticateMessage(data, data.MessageData)
 
 	if !valid {
-		log.Println("Failed to authenticate message")
+		authFailuresTotal.Inc()
+		p.logger.Warn("failed to authenticate gravitation response", zap.Stringer("from", s.Conn().RemotePeer()))
 		return
 	}
 
-	if p.resCallback(p.profile, p.orbit, *data) {
-		p.orbit = append(p.orbit, Body{peerID: s.Conn().RemotePeer().String(), profile: data.Profile})
+	if p.resCallback(p.profile, p.orbitSnapshot(), *data) {
+		p.addToOrbit(Body{peerID: s.Conn().RemotePeer().String(), profile: data.Profile})
+	}
+
+	entries := make([]subOrbitEntry, len(data.SubOrbit))
+	for i, body := range data.SubOrbit {
+		entries[i] = subOrbitEntry{peerID: body.PeerId, profile: body.Profile, attestation: body.Attestation}
 	}
+	p.mergeOrbitSubOrbit(entries)
 
 	// locate request data and remove it if found
+	p.requestsMu.Lock()
 	_, ok := p.requests[data.MessageData.Id]
 	if ok {
-		// remove request from map as we have processed it here
+		// remove request from map and cancel its stream context now that
+		// we have processed it here
 		delete(p.requests, data.MessageData.Id)
-	} else {
-		log.Println("Failed to locate request data boject for response")
+		if cancel, ok := p.cancels[data.MessageData.Id]; ok {
+			cancel()
+			delete(p.cancels, data.MessageData.Id)
+		}
+		if sentAt, ok := p.sentAt[data.MessageData.Id]; ok {
+			requestDurationSeconds.Observe(time.Since(sentAt).Seconds())
+			delete(p.sentAt, data.MessageData.Id)
+		}
+	}
+	p.requestsMu.Unlock()
+
+	if !ok {
+		p.logger.Warn("received response for unknown request", zap.String("message_id", data.MessageData.Id))
 		return
 	}
 
-	log.Printf("%s: Received gravitation response from %s. Message id:%s. Profile: %s SubOrbit: %s.", s.Conn().LocalPeer(), s.Conn().RemotePeer(), data.MessageData.Id, data.Profile, data.SubOrbit)
+	p.logger.Info("received gravitation response",
+		zap.Stringer("from", s.Conn().RemotePeer()),
+		zap.String("message_id", data.MessageData.Id),
+		zap.Strings("profile", data.Profile))
 	p.done <- true
 }
 
@@ -180,14 +340,22 @@ func (p *GravitationProtocol) onGravitationResponse(s inet.Stream) {
 // reqCallback gravitateReq:  Validation rules for request (== by default)
 // resCallback gravitateRes:  Validaiton rules for response (== by default)
 func (p *GravitationProtocol) Gravitation(host host.Host) bool {
+	return p.GravitationTo(host.ID())
+}
+
+// GravitationTo performs gravitation towards a bare peer ID rather than a
+// host.Host, for callers (e.g. DHT-based discovery) that have dialed a
+// peer but don't hold a host.Host for it.
+func (p *GravitationProtocol) GravitationTo(target peer.ID) bool {
 
-	log.Printf("%s: Sending gravitation to: %s....", p.node.ID(), host.ID())
+	p.logger.Debug("sending gravitation", zap.Stringer("to", target))
 
 	suborbit := []*p2p.GravitationRequest_SubOrbit{}
-	for _, body := range p.orbit {
+	for _, body := range p.orbitSnapshot() {
 		suborbit = append(suborbit, &(p2p.GravitationRequest_SubOrbit{
-			PeerId:  body.peerID,
-			Profile: body.profile}))
+			PeerId:      body.peerID,
+			Profile:     body.profile,
+			Attestation: body.attestation}))
 	}
 
 	// create message data
@@ -199,27 +367,43 @@ func (p *GravitationProtocol) Gravitation(host host.Host) bool {
 	// sign the data
 	signature, err := p.node.signProtoMessage(req)
 	if err != nil {
-		log.Println("failed to sign pb data")
+		p.logger.Error("failed to sign gravitation request", zap.Error(err))
 		return false
 	}
 
 	// add the signature to the message
 	req.MessageData.Sign = signature
 
-	s, err := p.node.NewStream(context.Background(), host.ID(), gravitationRequest)
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+
+	s, err := p.node.NewStream(ctx, target, gravitationRequest)
 	if err != nil {
-		log.Println(err)
+		p.logger.Error("failed to open gravitation request stream", zap.Error(err))
+		cancel()
 		return false
 	}
 
 	ok := p.node.sendProtoMessage(req, s)
-
 	if !ok {
+		s.Reset()
+		cancel()
 		return false
 	}
+	s.Close()
 
 	// store ref request so response handler has access to it
+	p.requestsMu.Lock()
 	p.requests[req.MessageData.Id] = req
-	log.Printf("%s: Gravitation to: %s was sent. Message Id: %s, Profile: %s SubOrbit: %s", p.node.ID(), host.ID(), req.MessageData.Id, req.Profile, req.SubOrbit)
+	p.cancels[req.MessageData.Id] = cancel
+	p.sentAt[req.MessageData.Id] = time.Now()
+	p.requestsMu.Unlock()
+
+	go p.evictOnTimeout(ctx, req.MessageData.Id)
+
+	requestsSentTotal.Inc()
+	p.logger.Info("gravitation sent",
+		zap.Stringer("to", target),
+		zap.String("message_id", req.MessageData.Id),
+		zap.Strings("profile", req.Profile))
 	return true
 }
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+)
+
+// newTestProtocol wires up a GravitationProtocol on a mocknet host so that
+// Gravitation() and its stream handlers run without any real transport.
+func newTestProtocol(t *testing.T, mn mocknet.Mocknet, profile []string) *GravitationProtocol {
+	t.Helper()
+
+	h, err := mn.GenPeer()
+	if err != nil {
+		t.Fatalf("GenPeer: %s", err)
+	}
+
+	node := NewNode(h)
+	done := make(chan bool, 1)
+	return NewGravitationProtocol(node, done, profile, nil, "", nil, nil)
+}
+
+// TestGravitationConcurrent exercises many concurrent Gravitation() calls
+// from a single requester against a single responder, proving that
+// GravitationProtocol.requests can be read and written from the resulting
+// goroutines without racing.
+func TestGravitationConcurrent(t *testing.T) {
+	mn := mocknet.New()
+	defer mn.Close()
+
+	requester := newTestProtocol(t, mn, []string{"earth"})
+	responder := newTestProtocol(t, mn, []string{"earth"})
+
+	if err := mn.LinkAll(); err != nil {
+		t.Fatalf("LinkAll: %s", err)
+	}
+	if err := mn.ConnectAllButSelf(); err != nil {
+		t.Fatalf("ConnectAllButSelf: %s", err)
+	}
+
+	const concurrency = 20
+	var wg sync.WaitGroup
+	results := make([]bool, concurrency)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = requester.Gravitation(responder.node)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, ok := range results {
+		if !ok {
+			t.Errorf("Gravitation call %d failed", i)
+		}
+	}
+
+	for i := 0; i < concurrency; i++ {
+		select {
+		case <-requester.done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for response %d", i)
+		}
+	}
+
+	requester.Close()
+}
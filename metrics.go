@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	requestsSentTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gravitation_requests_sent_total",
+		Help: "Total number of GravitationRequest messages sent.",
+	})
+	requestsReceivedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gravitation_requests_received_total",
+		Help: "Total number of GravitationRequest messages received.",
+	})
+	authFailuresTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gravitation_auth_failures_total",
+		Help: "Total number of messages that failed message authentication.",
+	})
+	orbitSize = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "gravitation_orbit_size",
+		Help: "Current number of bodies in the local orbit.",
+	})
+	requestDurationSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "gravitation_request_duration_seconds",
+		Help:    "Time between sending a GravitationRequest and receiving its GravitationResponse.",
+		Buckets: prometheus.DefBuckets,
+	})
+)
+
+// ServeMetrics registers the standard Prometheus handler on /metrics and
+// serves it on addr. It blocks, so callers typically run it in its own
+// goroutine.
+func ServeMetrics(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return http.ListenAndServe(addr, mux)
+}
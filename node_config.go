@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	libp2p "github.com/libp2p/go-libp2p"
+	connmgr "github.com/libp2p/go-libp2p-connmgr"
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	noise "github.com/libp2p/go-libp2p-noise"
+	peerstore "github.com/libp2p/go-libp2p-peerstore"
+	quic "github.com/libp2p/go-libp2p-quic-transport"
+	secio "github.com/libp2p/go-libp2p-secio"
+	tls "github.com/libp2p/go-libp2p-tls"
+	multiaddr "github.com/multiformats/go-multiaddr"
+)
+
+// identityPEMBlockType is the PEM block type used when persisting the
+// node's Ed25519 private key to disk.
+const identityPEMBlockType = "GRAVITATION PRIVATE KEY"
+
+// defaultLowWater and defaultHighWater bound the BasicConnMgr's trimming
+// behavior when the caller does not configure its own watermarks.
+const (
+	defaultLowWater  = 100
+	defaultHighWater = 400
+)
+
+// NodeConfig assembles the options needed to build a gravitation Node: its
+// transports, security stack, connection manager, and identity. Zero
+// values are filled in with sensible defaults by Build.
+type NodeConfig struct {
+	ListenAddrs    []multiaddr.Multiaddr // defaults to QUIC+TCP on an OS-assigned port if empty
+	BootstrapPeers []multiaddr.Multiaddr // multiaddrs of peers to dial before joining the DHT
+	LowWater       int                   // BasicConnMgr low watermark, defaults to defaultLowWater
+	HighWater      int                   // BasicConnMgr high watermark, defaults to defaultHighWater
+	IdentityPath   string                // PEM file the Ed25519 private key is persisted to / loaded from
+}
+
+// Flags registers NodeConfig's CLI flags on fs. Call ParseListenPort after
+// fs.Parse to turn --listen-port into the QUIC/TCP ListenAddrs NodeConfig
+// needs.
+func Flags(fs *flag.FlagSet) (*NodeConfig, *int) {
+	cfg := &NodeConfig{}
+
+	listenPort := fs.Int("listen-port", 0, "port to listen on for QUIC and TCP (0 picks a free port)")
+	fs.StringVar(&cfg.IdentityPath, "identity", "identity.pem", "path to a PEM-encoded Ed25519 private key; generated and persisted here if missing")
+
+	fs.Func("bootstrap", "multiaddr of a bootstrap peer (may be repeated)", func(addr string) error {
+		ma, err := multiaddr.NewMultiaddr(addr)
+		if err != nil {
+			return err
+		}
+		cfg.BootstrapPeers = append(cfg.BootstrapPeers, ma)
+		return nil
+	})
+
+	return cfg, listenPort
+}
+
+// ParseListenPort fills in cfg.ListenAddrs with QUIC and TCP multiaddrs on
+// port, the CLI flag value populated by Flags once fs.Parse has run.
+func (cfg *NodeConfig) ParseListenPort(port int) error {
+	quicAddr, err := multiaddr.NewMultiaddr(fmt.Sprintf("/ip4/0.0.0.0/udp/%d/quic", port))
+	if err != nil {
+		return err
+	}
+	tcpAddr, err := multiaddr.NewMultiaddr(fmt.Sprintf("/ip4/0.0.0.0/tcp/%d", port))
+	if err != nil {
+		return err
+	}
+
+	cfg.ListenAddrs = append(cfg.ListenAddrs, quicAddr, tcpAddr)
+	return nil
+}
+
+// Build assembles a libp2p host.Host from cfg: QUIC as the primary
+// transport with TCP as a fallback, TLS, Noise and SECIO as security transports,
+// a BasicConnMgr, and an Ed25519 identity loaded from (or generated and
+// persisted to) cfg.IdentityPath so the peer ID is stable across
+// restarts. It also dials cfg.BootstrapPeers on a best-effort basis so
+// the --bootstrap flag has an effect even before a GravitationProtocol's
+// DHT is running.
+func (cfg *NodeConfig) Build() (*Node, error) {
+	priv, err := cfg.loadOrGenerateIdentity()
+	if err != nil {
+		return nil, err
+	}
+
+	low, high := cfg.LowWater, cfg.HighWater
+	if low == 0 {
+		low = defaultLowWater
+	}
+	if high == 0 {
+		high = defaultHighWater
+	}
+	cm := connmgr.NewConnManager(low, high, 0)
+
+	opts := []libp2p.Option{
+		libp2p.Identity(priv),
+		libp2p.ConnectionManager(cm),
+		libp2p.Transport(quic.NewTransport),
+		libp2p.DefaultTransports,
+		libp2p.Security(tls.ID, tls.New),
+		libp2p.Security(noise.ID, noise.New),
+		libp2p.Security(secio.ID, secio.New),
+	}
+	if len(cfg.ListenAddrs) > 0 {
+		opts = append(opts, libp2p.ListenAddrs(cfg.ListenAddrs...))
+	}
+
+	h, err := libp2p.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	node := NewNode(h)
+	cfg.dialBootstrapPeers(node)
+	return node, nil
+}
+
+// dialBootstrapPeers connects node to each of cfg.BootstrapPeers,
+// skipping (rather than failing) any that can't be reached since a
+// single unreachable bootstrap peer shouldn't prevent startup.
+func (cfg *NodeConfig) dialBootstrapPeers(node *Node) {
+	for _, addr := range cfg.BootstrapPeers {
+		peerInfo, err := peerstore.InfoFromP2pAddr(addr)
+		if err != nil {
+			continue
+		}
+		node.Connect(context.Background(), *peerInfo)
+	}
+}
+
+// loadOrGenerateIdentity reads a PEM-encoded Ed25519 private key from
+// cfg.IdentityPath, generating and persisting a fresh one if the file
+// does not exist yet.
+func (cfg *NodeConfig) loadOrGenerateIdentity() (crypto.PrivKey, error) {
+	if cfg.IdentityPath == "" {
+		priv, _, err := crypto.GenerateEd25519Key(rand.Reader)
+		return priv, err
+	}
+
+	if raw, err := ioutil.ReadFile(cfg.IdentityPath); err == nil {
+		block, _ := pem.Decode(raw)
+		if block == nil {
+			return nil, fmt.Errorf("%s: not a PEM-encoded key", cfg.IdentityPath)
+		}
+		return crypto.UnmarshalPrivateKey(block.Bytes)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	priv, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := crypto.MarshalPrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: identityPEMBlockType, Bytes: raw})
+	if err := ioutil.WriteFile(cfg.IdentityPath, pemBytes, 0600); err != nil {
+		return nil, err
+	}
+
+	return priv, nil
+}
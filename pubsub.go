@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	p2p "github.com/assemblaj/GravitationProtocol/pb"
+
+	uuid "github.com/google/uuid"
+	peer "github.com/libp2p/go-libp2p-peer"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	protobufCodec "github.com/multiformats/go-multicodec/protobuf"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// decodeAdvertisement decodes a gossipsub payload into a GravitationAdvertisement.
+func decodeAdvertisement(raw []byte) (*p2p.GravitationAdvertisement, error) {
+	data := &p2p.GravitationAdvertisement{}
+	decoder := protobufCodec.Multicodec(nil).Decoder(bytes.NewReader(raw))
+	if err := decoder.Decode(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// encodeAdvertisement encodes a GravitationAdvertisement for publishing to
+// the orbit topic.
+func encodeAdvertisement(adv *p2p.GravitationAdvertisement) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := protobufCodec.Multicodec(nil).Encoder(&buf)
+	if err := encoder.Encode(adv); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// orbitTopic is the well-known gossipsub topic peers advertise their
+// profile and orbit membership on.
+const orbitTopic = "/gravitation/orbit/0.0.1"
+
+// heartbeatInterval is how often a node re-broadcasts its own profile and
+// orbit so that peers discovered only through the mesh keep their view
+// fresh.
+const heartbeatInterval = 30 * time.Second
+
+var (
+	advertisementsSent = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gravitation_orbit_advertisements_sent_total",
+		Help: "Total number of orbit advertisements broadcast to the orbit topic.",
+	})
+	advertisementsReceived = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gravitation_orbit_advertisements_received_total",
+		Help: "Total number of orbit advertisements received from the orbit topic.",
+	})
+	advertisementsRejected = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gravitation_orbit_advertisements_rejected_total",
+		Help: "Total number of orbit advertisements rejected by the topic validator.",
+	})
+)
+
+// OrbitBulletin wraps the pubsub subscription that lets peers discover and
+// maintain orbit membership without a prior unicast Gravitation() call.
+type OrbitBulletin struct {
+	ps     *pubsub.PubSub
+	topic  *pubsub.Topic
+	sub    *pubsub.Subscription
+	cancel context.CancelFunc
+}
+
+// EnableOrbitBulletin joins the orbit topic on ps, registers a signature
+// validator for incoming advertisements, and starts the heartbeat and
+// read loops. Call Close on the returned OrbitBulletin to tear both down.
+func (p *GravitationProtocol) EnableOrbitBulletin(ctx context.Context, ps *pubsub.PubSub) (*OrbitBulletin, error) {
+	if err := ps.RegisterTopicValidator(orbitTopic, p.validateAdvertisement); err != nil {
+		return nil, err
+	}
+
+	topic, err := ps.Join(orbitTopic)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := topic.Subscribe()
+	if err != nil {
+		return nil, err
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	bulletin := &OrbitBulletin{ps: ps, topic: topic, sub: sub, cancel: cancel}
+
+	go p.readOrbitAdvertisements(runCtx, sub)
+	go p.heartbeatOrbitAdvertisements(runCtx, topic)
+
+	return bulletin, nil
+}
+
+// Close cancels the bulletin's background loops and cancels the
+// subscription.
+func (b *OrbitBulletin) Close() {
+	b.cancel()
+	b.sub.Cancel()
+}
+
+// validateAdvertisement is registered as a pubsub topic validator so that
+// unsigned or misattributed advertisements never reach the application
+// handler in the first place.
+func (p *GravitationProtocol) validateAdvertisement(ctx context.Context, from peer.ID, msg *pubsub.Message) bool {
+	data, err := decodeAdvertisement(msg.Data)
+	if err != nil {
+		advertisementsRejected.Inc()
+		return false
+	}
+
+	if !p.node.authenticateMessage(data, data.MessageData) {
+		advertisementsRejected.Inc()
+		return false
+	}
+
+	return true
+}
+
+// readOrbitAdvertisements consumes validated advertisements from the
+// topic and applies reqCallback to decide whether to add the sender to
+// p.orbit, the same predicate used for unicast gravitation requests.
+func (p *GravitationProtocol) readOrbitAdvertisements(ctx context.Context, sub *pubsub.Subscription) {
+	for {
+		msg, err := sub.Next(ctx)
+		if err != nil {
+			return
+		}
+
+		if msg.ReceivedFrom == p.node.ID() {
+			continue
+		}
+
+		data, err := decodeAdvertisement(msg.Data)
+		if err != nil {
+			p.logger.Error("failed to decode orbit advertisement", zap.Error(err))
+			continue
+		}
+
+		advertisementsReceived.Inc()
+
+		req := p2p.GravitationRequest{MessageData: data.MessageData, Profile: data.Profile, SubOrbit: data.SubOrbit}
+		if p.reqCallback(p.profile, p.orbitSnapshot(), req) {
+			p.addToOrbit(Body{peerID: peer.ID(msg.From).String(), profile: data.Profile})
+		}
+
+		// third-party SubOrbit entries carried on the advertisement need
+		// the same attestation check as the unicast path, otherwise a
+		// peer could gossip an unverifiable orbit into ours
+		entries := make([]subOrbitEntry, len(data.SubOrbit))
+		for i, body := range data.SubOrbit {
+			entries[i] = subOrbitEntry{peerID: body.PeerId, profile: body.Profile, attestation: body.Attestation}
+		}
+		p.mergeOrbitSubOrbit(entries)
+	}
+}
+
+// heartbeatOrbitAdvertisements periodically re-broadcasts this node's own
+// profile and orbit so that peers who join the mesh after us still learn
+// about it.
+func (p *GravitationProtocol) heartbeatOrbitAdvertisements(ctx context.Context, topic *pubsub.Topic) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.publishOrbitAdvertisement(ctx, topic); err != nil {
+				p.logger.Error("failed to publish orbit advertisement", zap.Error(err))
+			}
+		}
+	}
+}
+
+// publishOrbitAdvertisement signs and publishes a single advertisement of
+// this node's current profile and orbit.
+func (p *GravitationProtocol) publishOrbitAdvertisement(ctx context.Context, topic *pubsub.Topic) error {
+	suborbit := []*p2p.GravitationRequest_SubOrbit{}
+	for _, body := range p.orbitSnapshot() {
+		suborbit = append(suborbit, &(p2p.GravitationRequest_SubOrbit{
+			PeerId:      body.peerID,
+			Profile:     body.profile,
+			Attestation: body.attestation}))
+	}
+
+	adv := &p2p.GravitationAdvertisement{
+		MessageData: p.node.NewMessageData(uuid.New().String(), false),
+		Profile:     p.profile,
+		SubOrbit:    suborbit,
+	}
+
+	signature, err := p.node.signProtoMessage(adv)
+	if err != nil {
+		return err
+	}
+	adv.MessageData.Sign = signature
+
+	data, err := encodeAdvertisement(adv)
+	if err != nil {
+		return err
+	}
+
+	if err := topic.Publish(ctx, data); err != nil {
+		return err
+	}
+
+	advertisementsSent.Inc()
+	return nil
+}
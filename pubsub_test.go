@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+)
+
+// TestOrbitBulletinRoundTrip joins two mocknet peers to the orbit topic and
+// checks that a published advertisement passes the topic validator and
+// lands in the other peer's orbit, the same path heartbeatOrbitAdvertisements
+// drives on a timer.
+func TestOrbitBulletinRoundTrip(t *testing.T) {
+	mn := mocknet.New()
+	defer mn.Close()
+
+	p1 := newTestProtocol(t, mn, []string{"earth"})
+	p2 := newTestProtocol(t, mn, []string{"earth"})
+
+	if err := mn.LinkAll(); err != nil {
+		t.Fatalf("LinkAll: %s", err)
+	}
+	if err := mn.ConnectAllButSelf(); err != nil {
+		t.Fatalf("ConnectAllButSelf: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ps1, err := pubsub.NewGossipSub(ctx, p1.node)
+	if err != nil {
+		t.Fatalf("NewGossipSub: %s", err)
+	}
+	ps2, err := pubsub.NewGossipSub(ctx, p2.node)
+	if err != nil {
+		t.Fatalf("NewGossipSub: %s", err)
+	}
+
+	b1, err := p1.EnableOrbitBulletin(ctx, ps1)
+	if err != nil {
+		t.Fatalf("EnableOrbitBulletin: %s", err)
+	}
+	defer b1.Close()
+
+	b2, err := p2.EnableOrbitBulletin(ctx, ps2)
+	if err != nil {
+		t.Fatalf("EnableOrbitBulletin: %s", err)
+	}
+	defer b2.Close()
+
+	// give the gossipsub mesh time to form between the two peers before
+	// publishing, otherwise the message has nowhere to go
+	deadline := time.Now().Add(5 * time.Second)
+	for len(ps1.ListPeers(orbitTopic)) == 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if len(ps1.ListPeers(orbitTopic)) == 0 {
+		t.Fatal("timed out waiting for orbit topic mesh to form")
+	}
+
+	if err := p1.publishOrbitAdvertisement(ctx, b1.topic); err != nil {
+		t.Fatalf("publishOrbitAdvertisement: %s", err)
+	}
+
+	deadline = time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, body := range p2.orbitSnapshot() {
+			if body.peerID == p1.node.ID().String() {
+				return
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	t.Fatalf("p2's orbit never picked up p1's advertisement: %+v", p2.orbitSnapshot())
+}